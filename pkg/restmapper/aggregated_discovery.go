@@ -0,0 +1,231 @@
+package restmapper
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	apidiscoveryv2 "k8s.io/api/apidiscovery/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// aggregatedDiscoveryAccept is the Accept header that requests the Kubernetes 1.27+
+// aggregated discovery document (a single APIGroupDiscoveryList covering every group,
+// version and resource) instead of the legacy, per-GroupVersion discovery documents.
+const aggregatedDiscoveryAccept = "application/json;g=apidiscovery.k8s.io;v=v2;as=APIGroupDiscoveryList"
+
+// ensureAggregated tries to populate the whole cache from aggregated discovery when it
+// hasn't tried yet, when the cached groups were invalidated, or when they are older than
+// the TTL (a 304 answer doesn't reset their age). It is a no-op unless
+// aggregatedDiscovery is enabled, and it
+// disables itself for the rest of the cache's life if the server doesn't support the
+// aggregated format (or a request for it fails), so later calls take the legacy per-GV
+// discovery path without paying for a doomed aggregated request every time.
+func (c *cache) ensureAggregated(ctx context.Context, discoveryClient discovery.DiscoveryInterface) error {
+	c.mutex.Lock()
+	enabled := c.aggregatedDiscovery
+	needsRefresh := !c.aggregatedAttempted || c.groups == nil || c.expired(c.groupsFetchedAt)
+	c.mutex.Unlock()
+
+	if !enabled || !needsRefresh {
+		return nil
+	}
+
+	ok := c.refreshAggregated(ctx, discoveryClient)
+
+	c.mutex.Lock()
+	c.aggregatedAttempted = true
+	if !ok {
+		c.aggregatedDiscovery = false
+	}
+	c.mutex.Unlock()
+	return nil
+}
+
+// refreshAggregated populates the entire cache from a pair of aggregated discovery
+// requests, one against /api (the core group) and one against /apis (all other
+// groups). It returns ok=false if the server doesn't support the aggregated format at
+// all, doesn't expose a raw REST client to ask it with, or a request for either path
+// fails (a transient error is treated the same as "unsupported" rather than failing the
+// caller outright), in which case the cache is left untouched and callers should fall
+// back to the legacy discovery calls.
+func (c *cache) refreshAggregated(ctx context.Context, discoveryClient discovery.DiscoveryInterface) bool {
+	log := log.FromContext(ctx)
+
+	restClient := c.restClientFor(discoveryClient)
+	if restClient == nil {
+		return false
+	}
+
+	core, ok := c.fetchAggregatedPath(ctx, restClient, "/api")
+	if !ok {
+		return false
+	}
+	named, ok := c.fetchAggregatedPath(ctx, restClient, "/apis")
+	if !ok {
+		return false
+	}
+
+	if core != nil || named != nil {
+		log.Info("populating restmapper cache from aggregated discovery")
+	}
+	if core != nil {
+		c.populateFromAggregatedDiscovery(core, true)
+	}
+	if named != nil {
+		c.populateFromAggregatedDiscovery(named, false)
+	}
+	return true
+}
+
+// fetchAggregatedPath fetches a single aggregated discovery document at path (one of
+// "/api" or "/apis"), using a conditional request when we already have an ETag for it.
+// It returns list=nil when the server replied 304 Not Modified (the cache is already up
+// to date and doesn't need to be touched), and ok=false when the server doesn't support
+// the aggregated format at all, or the request itself failed (network error, 5xx, a body
+// that doesn't decode as an APIGroupDiscoveryList) -- in every ok=false case the caller
+// should fall back to legacy discovery rather than treat this as a hard failure.
+func (c *cache) fetchAggregatedPath(ctx context.Context, restClient rest.Interface, path string) (*apidiscoveryv2.APIGroupDiscoveryList, bool) {
+	log := log.FromContext(ctx)
+
+	c.mutex.Lock()
+	etag := c.aggregatedETags[path]
+	c.mutex.Unlock()
+
+	result, err := conditionalGet(ctx, restClient, path, aggregatedDiscoveryAccept, etag)
+	if err != nil {
+		log.V(1).Info("aggregated discovery request failed, falling back to legacy discovery", "path", path, "err", err)
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	if c.aggregatedLastCheckedAt == nil {
+		c.aggregatedLastCheckedAt = make(map[string]time.Time)
+	}
+	c.aggregatedLastCheckedAt[path] = time.Now()
+	c.mutex.Unlock()
+
+	if result.notModified {
+		return nil, true
+	}
+
+	if !isAggregatedContentType(result.contentType) {
+		return nil, false
+	}
+
+	list := &apidiscoveryv2.APIGroupDiscoveryList{}
+	if err := json.Unmarshal(result.body, list); err != nil {
+		log.V(1).Info("error decoding aggregated discovery response, falling back to legacy discovery", "path", path, "err", err)
+		return nil, false
+	}
+
+	if result.etag != "" {
+		c.mutex.Lock()
+		if c.aggregatedETags == nil {
+			c.aggregatedETags = make(map[string]string)
+		}
+		c.aggregatedETags[path] = result.etag
+		c.mutex.Unlock()
+	}
+
+	return list, true
+}
+
+// isAggregatedContentType returns true if contentType identifies an APIGroupDiscoveryList
+// response, as opposed to the legacy APIGroupList/APIVersions/APIResourceList documents.
+func isAggregatedContentType(contentType string) bool {
+	return strings.Contains(contentType, "g=apidiscovery.k8s.io") && strings.Contains(contentType, "as=APIGroupDiscoveryList")
+}
+
+// populateFromAggregatedDiscovery replaces c.groups and c.groupVersions entries within
+// list's scope with its contents, as returned by /api or /apis. core distinguishes which
+// document this is: /api only ever describes the unnamed core group, /apis describes
+// every other group. Any previously cached group or GroupVersion that falls within this
+// document's scope but isn't present in list is removed -- mirroring the legacy discovery
+// path's full c.groups replace in findGroupInfo -- so a group or CRD deleted from the
+// cluster stops being served from stale cache instead of lingering forever.
+func (c *cache) populateFromAggregatedDiscovery(list *apidiscoveryv2.APIGroupDiscoveryList, core bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.groups == nil {
+		c.groups = make(map[string]metav1.APIGroup)
+	}
+
+	seenGroups := make(map[string]bool, len(list.Items))
+	seenGroupVersions := make(map[schema.GroupVersion]bool)
+
+	for _, groupDiscovery := range list.Items {
+		groupName := groupDiscovery.ObjectMeta.Name
+		seenGroups[groupName] = true
+
+		apiGroup := metav1.APIGroup{Name: groupName}
+		for _, v := range groupDiscovery.Versions {
+			apiGroup.Versions = append(apiGroup.Versions, metav1.GroupVersionForDiscovery{
+				GroupVersion: schema.GroupVersion{Group: groupName, Version: v.Version}.String(),
+				Version:      v.Version,
+			})
+		}
+		if len(apiGroup.Versions) > 0 {
+			apiGroup.PreferredVersion = apiGroup.Versions[0]
+		}
+		c.groups[groupName] = apiGroup
+
+		for _, v := range groupDiscovery.Versions {
+			gv := schema.GroupVersion{Group: groupName, Version: v.Version}
+			seenGroupVersions[gv] = true
+
+			kinds := make(map[string]cachedGVR)
+			toKind := make(map[string]string)
+			for _, r := range v.Resources {
+				if strings.Contains(r.Resource, "/") {
+					continue
+				}
+				if r.ResponseKind == nil || r.ResponseKind.Kind == "" {
+					continue
+				}
+
+				scope := meta.RESTScopeRoot
+				if r.Scope == apidiscoveryv2.ScopeNamespace {
+					scope = meta.RESTScopeNamespace
+				}
+				kinds[r.ResponseKind.Kind] = cachedGVR{Resource: r.Resource, Scope: scope}
+				toKind[r.Resource] = r.ResponseKind.Kind
+			}
+
+			c.groupVersions[gv] = &cachedGroupVersion{
+				gv:        gv,
+				kinds:     kinds,
+				toKind:    toKind,
+				owner:     c,
+				ttl:       c.ttl,
+				fetchedAt: time.Now(),
+			}
+		}
+	}
+
+	for name := range c.groups {
+		if isCoreGroupName(name) == core && !seenGroups[name] {
+			delete(c.groups, name)
+		}
+	}
+	for gv := range c.groupVersions {
+		if isCoreGroupName(gv.Group) == core && !seenGroupVersions[gv] {
+			delete(c.groupVersions, gv)
+		}
+	}
+
+	c.groupsFetchedAt = time.Now()
+}
+
+// isCoreGroupName reports whether name identifies the unnamed core API group (the one
+// served at /api rather than /apis/<group>).
+func isCoreGroupName(name string) bool {
+	return name == ""
+}
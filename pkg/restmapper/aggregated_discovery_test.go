@@ -0,0 +1,229 @@
+package restmapper
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	apidiscoveryv2 "k8s.io/api/apidiscovery/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+func newFakeDiscoveryClient(t *testing.T, server *httptest.Server) discovery.DiscoveryInterface {
+	t.Helper()
+	client, err := discovery.NewDiscoveryClientForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("building discovery client: %v", err)
+	}
+	return client
+}
+
+func sampleAggregatedList() *apidiscoveryv2.APIGroupDiscoveryList {
+	return &apidiscoveryv2.APIGroupDiscoveryList{
+		Items: []apidiscoveryv2.APIGroupDiscovery{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "apps"},
+				Versions: []apidiscoveryv2.APIVersionDiscovery{
+					{
+						Version: "v1",
+						Resources: []apidiscoveryv2.APIResourceDiscovery{
+							{
+								Resource:     "deployments",
+								ResponseKind: &metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+								Scope:        apidiscoveryv2.ScopeNamespace,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// aggregatedAwareServer serves an APIGroupDiscoveryList when the request carries the
+// aggregated discovery Accept header, and a legacy (non-aggregated) document otherwise.
+func aggregatedAwareServer(t *testing.T, aggregated map[string]*apidiscoveryv2.APIGroupDiscoveryList) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list, supported := aggregated[r.URL.Path]
+		if supported && r.Header.Get("Accept") == aggregatedDiscoveryAccept {
+			w.Header().Set("Content-Type", aggregatedDiscoveryAccept)
+			if err := json.NewEncoder(w).Encode(list); err != nil {
+				t.Fatalf("encoding response: %v", err)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api":
+			_, _ = w.Write([]byte(`{"kind":"APIVersions","versions":["v1"]}`))
+		default:
+			_, _ = w.Write([]byte(`{"kind":"APIGroupList","apiVersion":"v1","groups":[]}`))
+		}
+	}))
+}
+
+func TestFetchAggregatedDiscovery_Supported(t *testing.T) {
+	server := aggregatedAwareServer(t, map[string]*apidiscoveryv2.APIGroupDiscoveryList{
+		"/apis": sampleAggregatedList(),
+	})
+	defer server.Close()
+
+	c := newCache()
+	discoveryClient := newFakeDiscoveryClient(t, server)
+	got, ok := c.fetchAggregatedPath(context.Background(), discoveryClient.RESTClient(), "/apis")
+	if !ok {
+		t.Fatalf("expected aggregated discovery to be recognized")
+	}
+	if len(got.Items) != 1 || got.Items[0].ObjectMeta.Name != "apps" {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+}
+
+func TestFetchAggregatedDiscovery_FallsBackOnLegacyContentType(t *testing.T) {
+	server := aggregatedAwareServer(t, nil)
+	defer server.Close()
+
+	c := newCache()
+	discoveryClient := newFakeDiscoveryClient(t, server)
+	_, ok := c.fetchAggregatedPath(context.Background(), discoveryClient.RESTClient(), "/apis")
+	if ok {
+		t.Fatalf("expected ok=false (fallback) for a legacy content type")
+	}
+}
+
+func TestFetchAggregatedDiscovery_FallsBackOnRequestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	c := newCache()
+	discoveryClient := newFakeDiscoveryClient(t, server)
+	_, ok := c.fetchAggregatedPath(context.Background(), discoveryClient.RESTClient(), "/apis")
+	if ok {
+		t.Fatalf("expected ok=false (fallback) for a transient request error")
+	}
+}
+
+func TestCacheRefreshAggregated(t *testing.T) {
+	server := aggregatedAwareServer(t, map[string]*apidiscoveryv2.APIGroupDiscoveryList{
+		"/api":  {},
+		"/apis": sampleAggregatedList(),
+	})
+	defer server.Close()
+
+	c := newCache()
+	ok := c.refreshAggregated(context.Background(), newFakeDiscoveryClient(t, server))
+	if !ok {
+		t.Fatalf("expected aggregated discovery to be used")
+	}
+
+	gv := schema.GroupVersion{Group: "apps", Version: "v1"}
+	cgv, found := c.groupVersions[gv]
+	if !found {
+		t.Fatalf("expected %v to be populated from aggregated discovery", gv)
+	}
+	if cgv.kinds["Deployment"].Resource != "deployments" {
+		t.Fatalf("expected Deployment -> deployments mapping, got %+v", cgv.kinds)
+	}
+}
+
+func TestCacheRefreshAggregated_RemovesGroupsAndGroupVersionsDroppedFromDocument(t *testing.T) {
+	var apisList atomic.Pointer[apidiscoveryv2.APIGroupDiscoveryList]
+	apisList.Store(sampleAggregatedList())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == aggregatedDiscoveryAccept {
+			w.Header().Set("Content-Type", aggregatedDiscoveryAccept)
+			var list *apidiscoveryv2.APIGroupDiscoveryList
+			if r.URL.Path == "/api" {
+				list = &apidiscoveryv2.APIGroupDiscoveryList{}
+			} else {
+				list = apisList.Load()
+			}
+			if err := json.NewEncoder(w).Encode(list); err != nil {
+				t.Fatalf("encoding response: %v", err)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"APIGroupList","apiVersion":"v1","groups":[]}`))
+	}))
+	defer server.Close()
+
+	discoveryClient := newFakeDiscoveryClient(t, server)
+	c := newCache()
+	if ok := c.refreshAggregated(context.Background(), discoveryClient); !ok {
+		t.Fatalf("expected first refresh to use aggregated discovery")
+	}
+
+	gv := schema.GroupVersion{Group: "apps", Version: "v1"}
+	if _, found := c.groups["apps"]; !found {
+		t.Fatalf("expected group 'apps' to be populated after the first refresh")
+	}
+	if _, found := c.groupVersions[gv]; !found {
+		t.Fatalf("expected %v to be populated after the first refresh", gv)
+	}
+
+	// The "apps" group is removed from the cluster (e.g. a CRD's apiservice went away);
+	// the next /apis document no longer mentions it.
+	apisList.Store(&apidiscoveryv2.APIGroupDiscoveryList{})
+
+	if ok := c.refreshAggregated(context.Background(), discoveryClient); !ok {
+		t.Fatalf("expected second refresh to use aggregated discovery")
+	}
+
+	if _, found := c.groups["apps"]; found {
+		t.Fatalf("expected group 'apps' to be evicted once it's no longer in the aggregated document")
+	}
+	if _, found := c.groupVersions[gv]; found {
+		t.Fatalf("expected %v to be evicted once it's no longer in the aggregated document", gv)
+	}
+}
+
+func TestCacheRefreshAggregated_FallsBackOnTransientErrorWithoutFailing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	discoveryClient := newFakeDiscoveryClient(t, server)
+	c := newCache()
+	c.aggregatedDiscovery = true
+
+	if err := c.ensureAggregated(context.Background(), discoveryClient); err != nil {
+		t.Fatalf("ensureAggregated: %v", err)
+	}
+	if c.aggregatedDiscovery {
+		t.Fatalf("expected aggregated discovery to be disabled after a failed attempt, falling back to legacy discovery")
+	}
+	if !c.aggregatedAttempted {
+		t.Fatalf("expected aggregatedAttempted to be set so this isn't retried every call")
+	}
+}
+
+func TestRESTMapper_WithAggregatedDiscovery(t *testing.T) {
+	server := aggregatedAwareServer(t, map[string]*apidiscoveryv2.APIGroupDiscoveryList{
+		"/api":  {},
+		"/apis": sampleAggregatedList(),
+	})
+	defer server.Close()
+
+	m := NewRESTMapper(newFakeDiscoveryClient(t, server), WithAggregatedDiscovery(true))
+
+	mapping, err := m.RESTMapping(context.Background(), schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("RESTMapping: %v", err)
+	}
+	if mapping == nil || mapping.Resource.Resource != "deployments" {
+		t.Fatalf("unexpected mapping: %+v", mapping)
+	}
+}
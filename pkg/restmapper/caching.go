@@ -5,21 +5,77 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 // cache is our cache of schema information.
 type cache struct {
-	mutex         sync.Mutex
-	groups        map[string]metav1.APIGroup
-	groupVersions map[schema.GroupVersion]*cachedGroupVersion
+	mutex           sync.Mutex
+	groups          map[string]metav1.APIGroup
+	groupsFetchedAt time.Time
+	groupVersions   map[schema.GroupVersion]*cachedGroupVersion
+
+	// ttl is the maximum age of a cached entry before it is considered stale and
+	// re-fetched from discovery. A zero value disables TTL-based auto-refresh, and
+	// entries are kept until explicitly invalidated.
+	ttl time.Duration
+
+	// aggregatedDiscovery, when true, makes the cache try to populate itself from a
+	// single Kubernetes 1.27+ aggregated discovery document instead of one request per
+	// group/GroupVersion. It is automatically turned off after the first attempt if the
+	// server doesn't support the aggregated format, so subsequent calls take the legacy
+	// path without retrying the aggregated request every time.
+	aggregatedDiscovery bool
+	aggregatedAttempted bool
+
+	// restClient is an optional fallback used to issue raw conditional (If-None-Match)
+	// requests when the wrapped discovery.DiscoveryInterface's own RESTClient() returns
+	// nil. Configured via WithRESTClient; nil means conditional requests are skipped and
+	// every refresh is an ordinary, unconditional discovery call.
+	restClient rest.Interface
+
+	// aggregatedETags and aggregatedLastCheckedAt track conditional-request state for the
+	// "/api" and "/apis" aggregated discovery endpoints, keyed by path.
+	aggregatedETags         map[string]string
+	aggregatedLastCheckedAt map[string]time.Time
+
+	// warmWorkers bounds the parallelism of Warm/WarmAll. A value <= 0 means
+	// defaultWarmWorkers.
+	warmWorkers int
+
+	// negativeGroups records, per group name, that the group was absent from the last
+	// ServerGroups() response, with a backoff before it's worth checking again. GroupVersion
+	// misses are tracked per-entry on cachedGroupVersion instead, since one already exists
+	// for every GV we've been asked about.
+	negativeGroups map[string]*negativeEntry
+
+	// diskCacheDir, if non-empty, roots an on-disk discovery cache mirroring the layout
+	// client-go's CachedDiscoveryClient uses (and therefore kubectl), so CLI-style tools
+	// can share cache state across processes and survive restarts. Configured via
+	// WithDiskCache; the zero value disables disk caching entirely.
+	diskCacheDir string
+	// diskCacheMaxAge is how old an on-disk entry may be and still be trusted without
+	// reconfirming from the server. Zero (or negative) means any age is acceptable.
+	diskCacheMaxAge time.Duration
+}
+
+// restClientFor returns a rest.Interface for issuing raw conditional requests: the
+// DiscoveryInterface's own RESTClient() if it exposes one, falling back to the
+// RESTClient configured via WithRESTClient when it doesn't.
+func (c *cache) restClientFor(discoveryClient discovery.DiscoveryInterface) rest.Interface {
+	if rc := discoveryClient.RESTClient(); rc != nil {
+		return rc
+	}
+	return c.restClient
 }
 
 // newCache is the constructor for a cache.
@@ -29,14 +85,96 @@ func newCache() *cache {
 	}
 }
 
+// newCacheWithTTL is the constructor for a cache that automatically treats entries
+// older than ttl as stale, causing them to be re-fetched from discovery on next access.
+func newCacheWithTTL(ttl time.Duration) *cache {
+	c := newCache()
+	c.ttl = ttl
+	return c
+}
+
+// expired returns true if ttl-based auto-refresh is enabled and fetchedAt is older than the ttl.
+func (c *cache) expired(fetchedAt time.Time) bool {
+	if c.ttl <= 0 {
+		return false
+	}
+	return time.Since(fetchedAt) > c.ttl
+}
+
+// Invalidate clears the entire cache, forcing the next lookup of any group or
+// GroupVersion to rediscover it from the server.
+func (c *cache) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.groups = nil
+	c.groupsFetchedAt = time.Time{}
+	c.groupVersions = make(map[schema.GroupVersion]*cachedGroupVersion)
+	c.negativeGroups = nil
+}
+
+// InvalidateGroup clears the cached APIGroup entry for groupName, along with any
+// cached GroupVersions that belong to it.
+func (c *cache) InvalidateGroup(groupName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.invalidateGroupLocked(groupName)
+}
+
+// invalidateGroupLocked is InvalidateGroup without the locking; callers must hold c.mutex.
+func (c *cache) invalidateGroupLocked(groupName string) {
+	delete(c.groups, groupName)
+	delete(c.negativeGroups, groupName)
+	for gv := range c.groupVersions {
+		if gv.Group == groupName {
+			delete(c.groupVersions, gv)
+		}
+	}
+}
+
+// InvalidateGroupVersion clears the cached resource information for gv, forcing the
+// next findRESTMapping call for that GroupVersion to rediscover it from the server.
+func (c *cache) InvalidateGroupVersion(gv schema.GroupVersion) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.groupVersions, gv)
+}
+
 // findGroupInfo returns the APIGroup for the specified group, querying discovery if not cached.
 // If not found, returns APIGroup{}, false, nil
 func (c *cache) findGroupInfo(ctx context.Context, discovery discovery.DiscoveryInterface, groupName string) (metav1.APIGroup, bool, error) {
 	log := log.FromContext(ctx)
 
+	c.mutex.Lock()
+	if c.negativeGroups[groupName].current() {
+		c.mutex.Unlock()
+		return metav1.APIGroup{}, false, nil
+	}
+	c.mutex.Unlock()
+
+	if err := c.ensureAggregated(ctx, discovery); err != nil {
+		return metav1.APIGroup{}, false, err
+	}
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	if c.groups != nil && c.expired(c.groupsFetchedAt) {
+		c.groups = nil
+	}
+
+	if c.groups == nil {
+		if hostDir := c.diskCacheHostFor(discovery); hostDir != "" {
+			if groups, ok := c.loadGroupsFromDisk(hostDir); ok {
+				log.V(1).Info("loaded server groups from disk cache", "dir", hostDir)
+				c.groups = groups
+				c.groupsFetchedAt = time.Now()
+			}
+		}
+	}
+
 	if c.groups == nil {
 		log.Info("discovering server groups")
 		serverGroups, err := discovery.ServerGroups()
@@ -51,9 +189,19 @@ func (c *cache) findGroupInfo(ctx context.Context, discovery discovery.Discovery
 			groups[group.Name] = *group
 		}
 		c.groups = groups
+		c.groupsFetchedAt = time.Now()
+
+		if hostDir := c.diskCacheHostFor(discovery); hostDir != "" {
+			c.saveGroupsToDisk(hostDir, serverGroups)
+		}
 	}
 
 	group, found := c.groups[groupName]
+	if found {
+		delete(c.negativeGroups, groupName)
+	} else {
+		c.recordNegativeGroupLocked(groupName)
+	}
 	return group, found, nil
 }
 
@@ -64,6 +212,42 @@ type cachedGroupVersion struct {
 	kinds map[string]cachedGVR
 	// resource to kind
 	toKind map[string]string
+
+	// owner is the cache that created this cachedGroupVersion, used to evict it (and its
+	// group) from the parent cache when discovery reports it is no longer found.
+	owner *cache
+	// ttl is copied from owner.ttl at creation time; a zero value disables auto-refresh.
+	ttl       time.Duration
+	fetchedAt time.Time
+
+	// etag and lastCheckedAt track conditional-request state for this GroupVersion's
+	// discovery document, so a refresh that finds nothing changed costs a 304 rather
+	// than a full re-download. lastCheckedAt is bumped on every successful check,
+	// including 304s; fetchedAt (and therefore TTL-based staleness) only moves forward
+	// when the content actually changed.
+	etag          string
+	lastCheckedAt time.Time
+
+	// negative records that this GroupVersion was confirmed missing (no resources, or a
+	// 404) on the last attempt, and when it's worth reconfirming.
+	negative *negativeEntry
+}
+
+// expired returns true if ttl-based auto-refresh is enabled and the cached resources are stale.
+func (c *cachedGroupVersion) expired() bool {
+	if c.ttl <= 0 {
+		return false
+	}
+	return time.Since(c.fetchedAt) > c.ttl
+}
+
+// restClientFor returns a rest.Interface for issuing raw conditional requests, deferring
+// to the owning cache (which also knows about the WithRESTClient fallback).
+func (c *cachedGroupVersion) restClientFor(discoveryClient discovery.DiscoveryInterface) rest.Interface {
+	if c.owner != nil {
+		return c.owner.restClientFor(discoveryClient)
+	}
+	return discoveryClient.RESTClient()
 }
 
 // cachedGVR caches the information for a particular resource.
@@ -95,15 +279,27 @@ func (c *cache) KindFromGVR(gvr schema.GroupVersionResource) string {
 	return ""
 }
 
-// findRESTMapping returns the RESTMapping for the specified GVK, querying discovery if not cached.
-func (c *cache) findRESTMapping(ctx context.Context, discovery discovery.DiscoveryInterface, gv schema.GroupVersion, kind string) (*meta.RESTMapping, error) {
+// getOrCreateGroupVersion returns the cachedGroupVersion for gv, creating an empty one
+// (owned by c, inheriting c's TTL) if this is the first time gv has been seen.
+func (c *cache) getOrCreateGroupVersion(gv schema.GroupVersion) *cachedGroupVersion {
 	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	cached := c.groupVersions[gv]
 	if cached == nil {
-		cached = &cachedGroupVersion{gv: gv, toKind: make(map[string]string)}
+		cached = &cachedGroupVersion{gv: gv, toKind: make(map[string]string), owner: c, ttl: c.ttl}
 		c.groupVersions[gv] = cached
 	}
-	c.mutex.Unlock()
+	return cached
+}
+
+// findRESTMapping returns the RESTMapping for the specified GVK, querying discovery if not cached.
+func (c *cache) findRESTMapping(ctx context.Context, discovery discovery.DiscoveryInterface, gv schema.GroupVersion, kind string) (*meta.RESTMapping, error) {
+	if err := c.ensureAggregated(ctx, discovery); err != nil {
+		return nil, err
+	}
+
+	cached := c.getOrCreateGroupVersion(gv)
 	return cached.findRESTMapping(ctx, discovery, kind)
 }
 
@@ -132,23 +328,97 @@ func (c *cachedGroupVersion) fetch(ctx context.Context, discovery discovery.Disc
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	if c.kinds != nil {
+	if c.kinds != nil && !c.expired() {
 		return c.kinds, nil
 	}
 
+	if c.negative.current() {
+		return nil, nil
+	}
+
+	if hostDir := c.diskCacheHostFor(discovery); hostDir != "" {
+		if resourceList, ok := c.loadResourcesFromDisk(hostDir); ok {
+			kinds, toKind := kindsFromResourceList(resourceList)
+			c.negative = nil
+			c.kinds = kinds
+			c.toKind = toKind
+			c.fetchedAt = time.Now()
+			return kinds, nil
+		}
+	}
+
+	if restClient := c.restClientFor(discovery); restClient != nil && httpClientFor(restClient) != nil {
+		kinds, toKind, resourceList, notModified, err := c.fetchConditional(ctx, restClient)
+		if err != nil {
+			return nil, err
+		}
+		c.lastCheckedAt = time.Now()
+		if notModified {
+			log.V(1).Info("discovery unchanged for group/version (304)", "gv", c.gv.String())
+			return c.kinds, nil
+		}
+		if kinds == nil {
+			// fetchConditional already recorded a negative entry (or evicted this
+			// GroupVersion entirely, if it regressed from previously-cached data).
+			return nil, nil
+		}
+		c.negative = nil
+		c.kinds = kinds
+		c.toKind = toKind
+		c.fetchedAt = c.lastCheckedAt
+		if hostDir := c.diskCacheHostFor(discovery); hostDir != "" {
+			c.saveResourcesToDisk(hostDir, resourceList)
+		}
+		return kinds, nil
+	}
+
 	log.Info("discovering server resources for group/version", "gv", c.gv.String())
 	resourceList, err := discovery.ServerResourcesForGroupVersion(c.gv.String())
 	if err != nil {
 		// We treat "no match" as an empty result, but any other error percolates back up
-		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
+			if !c.fetchedAt.IsZero() {
+				// The GroupVersion was previously discoverable but now 404s. This happens when
+				// a CRD is deleted and recreated with a different set of versions, or when a
+				// version is marked unserved: the stale entry must not keep answering for it.
+				// Evict both this GroupVersion and its parent group so the next lookup
+				// rediscovers from scratch, rather than caching this as a negative result.
+				if c.owner != nil {
+					c.owner.InvalidateGroupVersion(c.gv)
+					c.owner.InvalidateGroup(c.gv.Group)
+				}
+				return nil, nil
+			}
+			// Never seen before: this is a genuine miss, not a regression, so record it as a
+			// negative result (with backoff) instead of hitting the API server every call.
+			c.recordNegative()
+			return nil, nil
+		}
+		if meta.IsNoMatchError(err) {
+			c.recordNegative()
 			return nil, nil
-		} else {
-			klog.Infof("unexpected error from ServerResourcesForGroupVersion(%v): %v", c.gv, err)
-			return nil, fmt.Errorf("error from ServerResourcesForGroupVersion(%v): %w", c.gv, err)
 		}
+		klog.Infof("unexpected error from ServerResourcesForGroupVersion(%v): %v", c.gv, err)
+		return nil, fmt.Errorf("error from ServerResourcesForGroupVersion(%v): %w", c.gv, err)
 	}
 
+	kinds, toKind := kindsFromResourceList(resourceList)
+	c.negative = nil
+	c.kinds = kinds
+	c.toKind = toKind
+	c.fetchedAt = time.Now()
+	if hostDir := c.diskCacheHostFor(discovery); hostDir != "" {
+		c.saveResourcesToDisk(hostDir, resourceList)
+	}
+	return kinds, nil
+}
+
+// kindsFromResourceList extracts the cache's internal kind/resource maps from an
+// APIResourceList, shared by the legacy discovery path, the conditional (ETag) path, and
+// disk-cache hydration so all three agree on what counts as a mapping.
+func kindsFromResourceList(resourceList *metav1.APIResourceList) (map[string]cachedGVR, map[string]string) {
 	kinds := make(map[string]cachedGVR)
+	toKind := make(map[string]string)
 	for i := range resourceList.APIResources {
 		resource := resourceList.APIResources[i]
 
@@ -165,8 +435,7 @@ func (c *cachedGroupVersion) fetch(ctx context.Context, discovery discovery.Disc
 			Resource: resource.Name,
 			Scope:    scope,
 		}
-		c.toKind[resource.Name] = resource.Kind
+		toKind[resource.Name] = resource.Kind
 	}
-	c.kinds = kinds
-	return kinds, nil
+	return kinds, toKind
 }
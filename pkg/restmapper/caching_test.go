@@ -0,0 +1,236 @@
+package restmapper
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+// noRESTClientDiscovery wraps a discovery.DiscoveryInterface but hides its RESTClient(),
+// forcing callers onto the legacy (non-conditional) discovery path. This lets the tests
+// below exercise the TTL/invalidation/eviction logic added here in isolation from the
+// ETag-aware conditional path added in a later request.
+type noRESTClientDiscovery struct {
+	discovery.DiscoveryInterface
+}
+
+func (noRESTClientDiscovery) RESTClient() rest.Interface {
+	return nil
+}
+
+func newGroupAndResourceServer(t *testing.T, group, version, kind, resource string) (*httptest.Server, *int32, *int32) {
+	t.Helper()
+	var groupRequests, resourceRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis":
+			atomic.AddInt32(&groupRequests, 1)
+			apiGroupList := metav1.APIGroupList{
+				Groups: []metav1.APIGroup{
+					{
+						Name:             group,
+						Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: group + "/" + version, Version: version}},
+						PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: group + "/" + version, Version: version},
+					},
+				},
+			}
+			_ = json.NewEncoder(w).Encode(apiGroupList)
+		case "/apis/" + group + "/" + version:
+			atomic.AddInt32(&resourceRequests, 1)
+			resourceList := metav1.APIResourceList{
+				APIResources: []metav1.APIResource{
+					{Name: resource, Kind: kind, Namespaced: true},
+				},
+			}
+			_ = json.NewEncoder(w).Encode(resourceList)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server, &groupRequests, &resourceRequests
+}
+
+func TestCache_InvalidateClearsGroupsAndGroupVersions(t *testing.T) {
+	server, _, _ := newGroupAndResourceServer(t, "widgets.example.com", "v1", "Widget", "widgets")
+	defer server.Close()
+	discoveryClient := noRESTClientDiscovery{newFakeDiscoveryClient(t, server)}
+	gv := schema.GroupVersion{Group: "widgets.example.com", Version: "v1"}
+
+	c := newCache()
+	if _, found, err := c.findGroupInfo(context.Background(), discoveryClient, "widgets.example.com"); err != nil || !found {
+		t.Fatalf("findGroupInfo: found=%v err=%v", found, err)
+	}
+	if _, err := c.findRESTMapping(context.Background(), discoveryClient, gv, "Widget"); err != nil {
+		t.Fatalf("findRESTMapping: %v", err)
+	}
+	if c.groups == nil || len(c.groupVersions) != 1 {
+		t.Fatalf("expected populated cache before Invalidate, got groups=%v groupVersions=%v", c.groups, c.groupVersions)
+	}
+
+	c.Invalidate()
+
+	if c.groups != nil {
+		t.Fatalf("expected groups to be cleared, got %v", c.groups)
+	}
+	if len(c.groupVersions) != 0 {
+		t.Fatalf("expected groupVersions to be cleared, got %v", c.groupVersions)
+	}
+}
+
+func TestCache_InvalidateGroupRemovesOnlyThatGroupsVersions(t *testing.T) {
+	c := newCache()
+	c.groups = map[string]metav1.APIGroup{
+		"g1": {Name: "g1"},
+		"g2": {Name: "g2"},
+	}
+	gv1 := schema.GroupVersion{Group: "g1", Version: "v1"}
+	gv2 := schema.GroupVersion{Group: "g2", Version: "v1"}
+	c.groupVersions[gv1] = &cachedGroupVersion{gv: gv1, owner: c}
+	c.groupVersions[gv2] = &cachedGroupVersion{gv: gv2, owner: c}
+
+	c.InvalidateGroup("g1")
+
+	if _, found := c.groups["g1"]; found {
+		t.Fatalf("expected g1 to be removed from groups")
+	}
+	if _, found := c.groups["g2"]; !found {
+		t.Fatalf("expected g2 to remain in groups")
+	}
+	if _, found := c.groupVersions[gv1]; found {
+		t.Fatalf("expected %v to be removed from groupVersions", gv1)
+	}
+	if _, found := c.groupVersions[gv2]; !found {
+		t.Fatalf("expected %v to remain in groupVersions", gv2)
+	}
+}
+
+func TestCache_InvalidateGroupVersionLeavesGroupAndOtherVersionsAlone(t *testing.T) {
+	c := newCache()
+	c.groups = map[string]metav1.APIGroup{"g1": {Name: "g1"}}
+	gvV1 := schema.GroupVersion{Group: "g1", Version: "v1"}
+	gvV2 := schema.GroupVersion{Group: "g1", Version: "v2"}
+	c.groupVersions[gvV1] = &cachedGroupVersion{gv: gvV1, owner: c}
+	c.groupVersions[gvV2] = &cachedGroupVersion{gv: gvV2, owner: c}
+
+	c.InvalidateGroupVersion(gvV1)
+
+	if _, found := c.groupVersions[gvV1]; found {
+		t.Fatalf("expected %v to be removed", gvV1)
+	}
+	if _, found := c.groupVersions[gvV2]; !found {
+		t.Fatalf("expected %v to remain", gvV2)
+	}
+	if _, found := c.groups["g1"]; !found {
+		t.Fatalf("expected the group itself to be untouched by InvalidateGroupVersion")
+	}
+}
+
+func TestCachedGroupVersion_TTLExpiryTriggersRefetch(t *testing.T) {
+	server, _, resourceRequests := newGroupAndResourceServer(t, "widgets.example.com", "v1", "Widget", "widgets")
+	defer server.Close()
+	discoveryClient := noRESTClientDiscovery{newFakeDiscoveryClient(t, server)}
+	gv := schema.GroupVersion{Group: "widgets.example.com", Version: "v1"}
+
+	const ttl = 20 * time.Millisecond
+	c := newCacheWithTTL(ttl)
+
+	if _, err := c.findRESTMapping(context.Background(), discoveryClient, gv, "Widget"); err != nil {
+		t.Fatalf("findRESTMapping (first): %v", err)
+	}
+	if _, err := c.findRESTMapping(context.Background(), discoveryClient, gv, "Widget"); err != nil {
+		t.Fatalf("findRESTMapping (within TTL): %v", err)
+	}
+	if got := atomic.LoadInt32(resourceRequests); got != 1 {
+		t.Fatalf("expected 1 request while the entry is still fresh, got %d", got)
+	}
+
+	time.Sleep(2 * ttl)
+
+	if _, err := c.findRESTMapping(context.Background(), discoveryClient, gv, "Widget"); err != nil {
+		t.Fatalf("findRESTMapping (after TTL): %v", err)
+	}
+	if got := atomic.LoadInt32(resourceRequests); got != 2 {
+		t.Fatalf("expected the expired entry to trigger a second request, got %d", got)
+	}
+}
+
+func TestCachedGroupVersion_RegressionAfterPriorSuccessEvictsGroupAndGroupVersion(t *testing.T) {
+	const group, version = "widgets.example.com", "v1"
+	var gone int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis":
+			apiGroupList := metav1.APIGroupList{
+				Groups: []metav1.APIGroup{
+					{
+						Name:             group,
+						Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: group + "/" + version, Version: version}},
+						PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: group + "/" + version, Version: version},
+					},
+				},
+			}
+			_ = json.NewEncoder(w).Encode(apiGroupList)
+		case "/apis/" + group + "/" + version:
+			if atomic.LoadInt32(&gone) != 0 {
+				http.NotFound(w, r)
+				return
+			}
+			resourceList := metav1.APIResourceList{
+				APIResources: []metav1.APIResource{
+					{Name: "widgets", Kind: "Widget", Namespaced: true},
+				},
+			}
+			_ = json.NewEncoder(w).Encode(resourceList)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	discoveryClient := noRESTClientDiscovery{newFakeDiscoveryClient(t, server)}
+	gv := schema.GroupVersion{Group: group, Version: version}
+
+	// A short TTL is needed so the second findRESTMapping call below actually triggers a
+	// re-fetch instead of being served from the (still unexpired) cache: without TTL-based
+	// refresh, the regression-eviction path in fetch() would never run.
+	const ttl = 20 * time.Millisecond
+	c := newCacheWithTTL(ttl)
+	if _, found, err := c.findGroupInfo(context.Background(), discoveryClient, group); err != nil || !found {
+		t.Fatalf("findGroupInfo: found=%v err=%v", found, err)
+	}
+	mapping, err := c.findRESTMapping(context.Background(), discoveryClient, gv, "Widget")
+	if err != nil || mapping == nil {
+		t.Fatalf("findRESTMapping (before deletion): mapping=%+v err=%v", mapping, err)
+	}
+
+	// Simulate the CRD being deleted and recreated with a different set of served
+	// versions: the GroupVersion that used to resolve now 404s.
+	atomic.StoreInt32(&gone, 1)
+	time.Sleep(2 * ttl)
+
+	mapping, err = c.findRESTMapping(context.Background(), discoveryClient, gv, "Widget")
+	if err != nil {
+		t.Fatalf("findRESTMapping (after deletion): %v", err)
+	}
+	if mapping != nil {
+		t.Fatalf("expected no mapping once the GroupVersion regresses to 404, got %+v", mapping)
+	}
+
+	if _, found := c.groupVersions[gv]; found {
+		t.Fatalf("expected the regressed GroupVersion to be evicted from the cache")
+	}
+	if _, found := c.groups[group]; found {
+		t.Fatalf("expected the parent group to be evicted along with its GroupVersion")
+	}
+}
@@ -0,0 +1,172 @@
+package restmapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+)
+
+// unsafeDiskCacheChars matches everything but word characters, slashes, dots and
+// parentheses: the same
+// set client-go's disk-backed discovery client replaces with "_" when turning a server
+// host into a directory name.
+var unsafeDiskCacheChars = regexp.MustCompile(`[^(\w/.)]`)
+
+// diskCacheHostDir returns the per-host subdirectory of dir for host, mirroring the
+// layout client-go's CachedDiscoveryClient uses under ~/.kube/cache/discovery, so a cache
+// rooted at the same directory can be shared with kubectl and survives process restarts.
+func diskCacheHostDir(dir, host string) string {
+	schemelessHost := strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	safeHost := unsafeDiskCacheChars.ReplaceAllString(schemelessHost, "_")
+	return filepath.Join(dir, safeHost)
+}
+
+// diskCacheHostFor resolves the per-host cache directory for discoveryClient, or "" if
+// disk caching is disabled (WithDiskCache wasn't used) or the host can't be determined,
+// e.g. a fake discovery client in tests with no REST client backing it.
+func (c *cache) diskCacheHostFor(discoveryClient discovery.DiscoveryInterface) string {
+	if c.diskCacheDir == "" {
+		return ""
+	}
+	restClient := c.restClientFor(discoveryClient)
+	if restClient == nil {
+		return ""
+	}
+	host := restClient.Get().URL().Host
+	if host == "" {
+		return ""
+	}
+	return diskCacheHostDir(c.diskCacheDir, host)
+}
+
+// diskCacheHostFor defers to the owning cache, which also knows about diskCacheMaxAge.
+func (c *cachedGroupVersion) diskCacheHostFor(discoveryClient discovery.DiscoveryInterface) string {
+	if c.owner == nil {
+		return ""
+	}
+	return c.owner.diskCacheHostFor(discoveryClient)
+}
+
+// diskCacheFresh reports whether the file at path exists and was written within maxAge.
+// A non-positive maxAge means no freshness requirement, matching the cache's own
+// no-TTL convention.
+func diskCacheFresh(path string, maxAge time.Duration) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if maxAge <= 0 {
+		return true
+	}
+	return time.Since(info.ModTime()) <= maxAge
+}
+
+// loadGroupsFromDisk reads servergroups.json from hostDir, returning ok=false if it's
+// missing, unreadable, or older than c.diskCacheMaxAge.
+func (c *cache) loadGroupsFromDisk(hostDir string) (groups map[string]metav1.APIGroup, ok bool) {
+	path := filepath.Join(hostDir, "servergroups.json")
+	if !diskCacheFresh(path, c.diskCacheMaxAge) {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	list := &metav1.APIGroupList{}
+	if err := json.Unmarshal(data, list); err != nil {
+		return nil, false
+	}
+	groups = make(map[string]metav1.APIGroup, len(list.Groups))
+	for i := range list.Groups {
+		groups[list.Groups[i].Name] = list.Groups[i]
+	}
+	return groups, true
+}
+
+// saveGroupsToDisk writes serverGroups to servergroups.json under hostDir.
+func (c *cache) saveGroupsToDisk(hostDir string, serverGroups *metav1.APIGroupList) {
+	path := filepath.Join(hostDir, "servergroups.json")
+	if err := writeJSONFileAtomic(path, serverGroups); err != nil {
+		klog.Infof("error writing discovery disk cache %s: %v", path, err)
+	}
+}
+
+// diskResourcesPath returns the path serverresources.json for gv lives at under hostDir:
+// "<hostDir>/v1/serverresources.json" for the core group, or
+// "<hostDir>/<group>/<version>/serverresources.json" otherwise. filepath.Join drops empty
+// elements, so the core group's empty Group naturally collapses to a single segment.
+func diskResourcesPath(hostDir string, gv schema.GroupVersion) string {
+	return filepath.Join(hostDir, gv.Group, gv.Version, "serverresources.json")
+}
+
+// loadResourcesFromDisk reads the cached APIResourceList for this GroupVersion from
+// hostDir, returning ok=false if it's missing, unreadable, or older than the owning
+// cache's diskCacheMaxAge.
+func (c *cachedGroupVersion) loadResourcesFromDisk(hostDir string) (resourceList *metav1.APIResourceList, ok bool) {
+	var maxAge time.Duration
+	if c.owner != nil {
+		maxAge = c.owner.diskCacheMaxAge
+	}
+	path := diskResourcesPath(hostDir, c.gv)
+	if !diskCacheFresh(path, maxAge) {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	resourceList = &metav1.APIResourceList{}
+	if err := json.Unmarshal(data, resourceList); err != nil {
+		return nil, false
+	}
+	return resourceList, true
+}
+
+// saveResourcesToDisk writes resourceList to serverresources.json under hostDir.
+func (c *cachedGroupVersion) saveResourcesToDisk(hostDir string, resourceList *metav1.APIResourceList) {
+	path := diskResourcesPath(hostDir, c.gv)
+	if err := writeJSONFileAtomic(path, resourceList); err != nil {
+		klog.Infof("error writing discovery disk cache %s: %v", path, err)
+	}
+}
+
+// writeJSONFileAtomic marshals v as JSON and writes it to path via a temp file in the
+// same directory renamed into place, so a concurrent reader never observes a partially
+// written file.
+func writeJSONFileAtomic(path string, v interface{}) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating directory %s: %w", dir, err)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshaling %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file in %s: %w", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temp file for %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("renaming into place %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package restmapper
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDiskCacheHostDir_StripsSchemeAndSanitizes(t *testing.T) {
+	got := diskCacheHostDir("/cache", "https://1.2.3.4:6443")
+	want := filepath.Join("/cache", "1.2.3.4_6443")
+	if got != want {
+		t.Errorf("diskCacheHostDir() = %q, want %q", got, want)
+	}
+}
+
+func TestDiskResourcesPath_CoreGroupCollapsesToSingleSegment(t *testing.T) {
+	got := diskResourcesPath("/cache/host", schema.GroupVersion{Version: "v1"})
+	want := filepath.Join("/cache/host", "v1", "serverresources.json")
+	if got != want {
+		t.Errorf("diskResourcesPath() = %q, want %q", got, want)
+	}
+
+	got = diskResourcesPath("/cache/host", schema.GroupVersion{Group: "apps", Version: "v1"})
+	want = filepath.Join("/cache/host", "apps", "v1", "serverresources.json")
+	if got != want {
+		t.Errorf("diskResourcesPath() = %q, want %q", got, want)
+	}
+}
+
+// diskCacheServer counts how many times its handler is hit, so tests can assert that a
+// warm on-disk cache is used in place of a server round-trip.
+type diskCacheServer struct {
+	*httptest.Server
+	groupRequests, resourceRequests int32
+}
+
+func newDiskCacheServer(t *testing.T) *diskCacheServer {
+	t.Helper()
+	s := &diskCacheServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis":
+			atomic.AddInt32(&s.groupRequests, 1)
+			apiGroupList := metav1.APIGroupList{
+				Groups: []metav1.APIGroup{
+					{
+						Name:             "widgets.example.com",
+						Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "widgets.example.com/v1", Version: "v1"}},
+						PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "widgets.example.com/v1", Version: "v1"},
+					},
+				},
+			}
+			_ = json.NewEncoder(w).Encode(apiGroupList)
+		case "/apis/widgets.example.com/v1":
+			atomic.AddInt32(&s.resourceRequests, 1)
+			resourceList := metav1.APIResourceList{
+				APIResources: []metav1.APIResource{
+					{Name: "widgets", Kind: "Widget", Namespaced: true},
+				},
+			}
+			_ = json.NewEncoder(w).Encode(resourceList)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return s
+}
+
+func TestCache_DiskCacheHydratesGroupVersionWithoutServerRoundTrip(t *testing.T) {
+	server := newDiskCacheServer(t)
+	defer server.Close()
+	discoveryClient := newFakeDiscoveryClient(t, server.Server)
+	gv := schema.GroupVersion{Group: "widgets.example.com", Version: "v1"}
+
+	dir := t.TempDir()
+	c := newCache()
+	c.diskCacheDir = dir
+
+	mapping, err := c.findRESTMapping(context.Background(), discoveryClient, gv, "Widget")
+	if err != nil {
+		t.Fatalf("findRESTMapping: %v", err)
+	}
+	if mapping == nil {
+		t.Fatalf("expected a mapping for Widget")
+	}
+	if got := atomic.LoadInt32(&server.resourceRequests); got != 1 {
+		t.Fatalf("expected 1 resource request to populate the disk cache, got %d", got)
+	}
+
+	hostDir := c.diskCacheHostFor(discoveryClient)
+	if _, err := os.Stat(diskResourcesPath(hostDir, gv)); err != nil {
+		t.Fatalf("expected serverresources.json to be written: %v", err)
+	}
+
+	// A fresh cache (simulating a new process) backed by the same directory should
+	// hydrate straight from disk rather than hitting the server again.
+	c2 := newCache()
+	c2.diskCacheDir = dir
+	mapping2, err := c2.findRESTMapping(context.Background(), discoveryClient, gv, "Widget")
+	if err != nil {
+		t.Fatalf("findRESTMapping (second cache): %v", err)
+	}
+	if mapping2 == nil || mapping2.Resource.Resource != "widgets" {
+		t.Fatalf("expected a hydrated mapping for Widget, got %+v", mapping2)
+	}
+	if got := atomic.LoadInt32(&server.resourceRequests); got != 1 {
+		t.Fatalf("expected the second cache to be satisfied from disk (still 1 request), got %d", got)
+	}
+}
+
+func TestCache_DiskCacheHydratesGroupsWithoutServerRoundTrip(t *testing.T) {
+	server := newDiskCacheServer(t)
+	defer server.Close()
+	discoveryClient := newFakeDiscoveryClient(t, server.Server)
+
+	dir := t.TempDir()
+	c := newCache()
+	c.diskCacheDir = dir
+
+	if _, found, err := c.findGroupInfo(context.Background(), discoveryClient, "widgets.example.com"); err != nil || !found {
+		t.Fatalf("findGroupInfo: found=%v err=%v", found, err)
+	}
+	if got := atomic.LoadInt32(&server.groupRequests); got != 1 {
+		t.Fatalf("expected 1 ServerGroups request, got %d", got)
+	}
+
+	c2 := newCache()
+	c2.diskCacheDir = dir
+	if _, found, err := c2.findGroupInfo(context.Background(), discoveryClient, "widgets.example.com"); err != nil || !found {
+		t.Fatalf("findGroupInfo (second cache): found=%v err=%v", found, err)
+	}
+	if got := atomic.LoadInt32(&server.groupRequests); got != 1 {
+		t.Fatalf("expected the second cache to be satisfied from disk (still 1 request), got %d", got)
+	}
+}
+
+func TestCache_DiskCacheMaxAgeExpiresStaleEntries(t *testing.T) {
+	server := newDiskCacheServer(t)
+	defer server.Close()
+	discoveryClient := newFakeDiscoveryClient(t, server.Server)
+	gv := schema.GroupVersion{Group: "widgets.example.com", Version: "v1"}
+
+	dir := t.TempDir()
+	c := newCache()
+	c.diskCacheDir = dir
+	if _, err := c.findRESTMapping(context.Background(), discoveryClient, gv, "Widget"); err != nil {
+		t.Fatalf("findRESTMapping: %v", err)
+	}
+
+	hostDir := c.diskCacheHostFor(discoveryClient)
+	staleTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(diskResourcesPath(hostDir, gv), staleTime, staleTime); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	c2 := newCache()
+	c2.diskCacheDir = dir
+	c2.diskCacheMaxAge = time.Minute
+	if _, err := c2.findRESTMapping(context.Background(), discoveryClient, gv, "Widget"); err != nil {
+		t.Fatalf("findRESTMapping (second cache): %v", err)
+	}
+	if got := atomic.LoadInt32(&server.resourceRequests); got != 2 {
+		t.Fatalf("expected the stale on-disk entry to be ignored and the server re-queried, got %d requests", got)
+	}
+}
+
+func TestWithDiskCache_EmptyDirIsNoOp(t *testing.T) {
+	c := newCache()
+	WithDiskCache("", time.Minute)(c)
+	if c.diskCacheDir != "" {
+		t.Fatalf("expected diskCacheDir to remain empty, got %q", c.diskCacheDir)
+	}
+	if hostDir := c.diskCacheHostFor(nil); hostDir != "" {
+		t.Fatalf("expected diskCacheHostFor to be a no-op when disk caching is disabled, got %q", hostDir)
+	}
+}
@@ -0,0 +1,165 @@
+package restmapper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// conditionalResult is the outcome of a conditional (If-None-Match) GET against one of
+// the API server's discovery endpoints.
+type conditionalResult struct {
+	statusCode  int
+	body        []byte
+	etag        string
+	contentType string
+	notModified bool
+}
+
+// conditionalGet issues a GET against path with the given Accept header, sending
+// If-None-Match: etag when etag is non-empty, and reports the response's ETag (if any)
+// back to the caller to store for next time.
+//
+// This bypasses the high-level rest.Request.Do/DoRaw helpers: they don't expose response
+// headers (we need ETag) and don't treat 304 as anything but an error. Instead we resolve
+// the request URL via rest.Request.URL() and execute it ourselves against the
+// RESTClient's own *http.Client, so the request still goes through whatever transport
+// (auth, TLS) the discovery client was configured with. Since that skips rest.Request
+// entirely, it would also skip its client-side QPS/Burst throttling; we replicate that
+// explicitly below via the RESTClient's own rate limiter before issuing the request.
+func conditionalGet(ctx context.Context, restClient rest.Interface, path, accept, etag string) (*conditionalResult, error) {
+	httpClient := httpClientFor(restClient)
+	if httpClient == nil {
+		return nil, fmt.Errorf("REST client %T does not expose an *http.Client for conditional requests", restClient)
+	}
+
+	if limiter := rateLimiterFor(restClient); limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("client rate limiter Wait returned an error: %w", err)
+		}
+	}
+
+	reqURL := restClient.Get().AbsPath(path).URL()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("building conditional request for %s: %w", path, err)
+	}
+	httpReq.Header.Set("Accept", accept)
+	if etag != "" {
+		httpReq.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("error issuing conditional request for %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return &conditionalResult{statusCode: resp.StatusCode, etag: etag, notModified: true}, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response from %s: %w", path, err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, path)
+	}
+
+	return &conditionalResult{
+		statusCode:  resp.StatusCode,
+		body:        body,
+		etag:        resp.Header.Get("ETag"),
+		contentType: resp.Header.Get("Content-Type"),
+	}, nil
+}
+
+// httpClientFor extracts the *http.Client backing restClient, if any. Only the concrete
+// *rest.RESTClient exposes one; other implementations (notably the fakes used in tests)
+// return nil, in which case callers should skip conditional requests entirely and fall
+// back to the ordinary discovery.DiscoveryInterface methods.
+func httpClientFor(restClient rest.Interface) *http.Client {
+	concrete, ok := restClient.(*rest.RESTClient)
+	if !ok || concrete == nil {
+		return nil
+	}
+	return concrete.Client
+}
+
+// rateLimiterFor extracts the client-side QPS/Burst rate limiter backing restClient, if
+// any, so conditionalGet's raw requests are throttled the same way rest.Request.Do would
+// throttle them. Only the concrete *rest.RESTClient exposes one via GetRateLimiter(); a nil
+// return (including for non-RESTClient implementations) means no throttling is applied,
+// matching a rest.Request with no rate limiter configured.
+func rateLimiterFor(restClient rest.Interface) flowcontrol.RateLimiter {
+	concrete, ok := restClient.(*rest.RESTClient)
+	if !ok || concrete == nil {
+		return nil
+	}
+	return concrete.GetRateLimiter()
+}
+
+// legacyDiscoveryPath returns the discovery endpoint for gv: "/api/<version>" for the
+// core (unnamed) group, "/apis/<group>/<version>" otherwise.
+func legacyDiscoveryPath(gv schema.GroupVersion) string {
+	if gv.Group == "" {
+		return "/api/" + gv.Version
+	}
+	return "/apis/" + gv.Group + "/" + gv.Version
+}
+
+// fetchConditional fetches this GroupVersion's resource list via a conditional
+// (If-None-Match) raw request, so a refresh that finds nothing changed costs a 304
+// rather than a full APIResourceList re-download. notModified is true when the server
+// confirmed our cached c.etag is still current, in which case kinds/toKind/resourceList
+// are nil and the existing c.kinds/c.toKind should be kept as-is. resourceList is the
+// decoded document behind kinds/toKind, returned so callers can persist the exact bytes
+// kubectl's own disk cache would have stored.
+func (c *cachedGroupVersion) fetchConditional(ctx context.Context, restClient rest.Interface) (kinds map[string]cachedGVR, toKind map[string]string, resourceList *metav1.APIResourceList, notModified bool, err error) {
+	path := legacyDiscoveryPath(c.gv)
+
+	result, err := conditionalGet(ctx, restClient, path, "application/json", c.etag)
+	if err != nil {
+		return nil, nil, nil, false, fmt.Errorf("error from GET %s: %w", path, err)
+	}
+	if result.notModified {
+		return nil, nil, nil, true, nil
+	}
+	if result.statusCode == http.StatusNotFound {
+		// Mirrors the IsNotFound handling in the legacy discovery path: if this
+		// GroupVersion was previously discoverable and now 404s, evict it (and its
+		// group) rather than caching an empty result forever. If it was never seen
+		// before, this is a genuine miss: record it as negative (with backoff) instead.
+		if !c.fetchedAt.IsZero() {
+			if c.owner != nil {
+				c.owner.InvalidateGroupVersion(c.gv)
+				c.owner.InvalidateGroup(c.gv.Group)
+			}
+			return nil, nil, nil, false, nil
+		}
+		c.recordNegative()
+		return nil, nil, nil, false, nil
+	}
+
+	resourceList = &metav1.APIResourceList{}
+	if err := json.Unmarshal(result.body, resourceList); err != nil {
+		return nil, nil, nil, false, fmt.Errorf("error decoding response from %s: %w", path, err)
+	}
+
+	kinds, toKind = kindsFromResourceList(resourceList)
+
+	if result.etag != "" {
+		c.etag = result.etag
+	}
+	return kinds, toKind, resourceList, false, nil
+}
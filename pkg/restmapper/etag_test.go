@@ -0,0 +1,76 @@
+package restmapper
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCachedGroupVersionFetchConditional_NotModified(t *testing.T) {
+	const etag = `"v1"`
+	resourceList := &metav1.APIResourceList{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", Namespaced: true},
+		},
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resourceList); err != nil {
+			t.Fatalf("encoding response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	discoveryClient := newFakeDiscoveryClient(t, server)
+	gv := schema.GroupVersion{Group: "apps", Version: "v1"}
+
+	c := newCache()
+	mapping, err := c.findRESTMapping(context.Background(), discoveryClient, gv, "Deployment")
+	if err != nil {
+		t.Fatalf("findRESTMapping (first): %v", err)
+	}
+	if mapping == nil || mapping.Resource.Resource != "deployments" {
+		t.Fatalf("unexpected mapping: %+v", mapping)
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+
+	cgv := c.groupVersions[gv]
+	if cgv.etag != etag {
+		t.Fatalf("expected cached etag %q, got %q", etag, cgv.etag)
+	}
+
+	// Simulate the cached entry going stale via TTL expiry, without losing the ETag or the
+	// cached kinds themselves (an invalidated entry would lose both together, via
+	// InvalidateGroupVersion deleting the cachedGroupVersion entirely), and confirm the
+	// refresh is answered with a 304 that leaves the cached kinds untouched.
+	cgv.ttl = time.Minute
+	cgv.fetchedAt = time.Now().Add(-time.Hour)
+
+	mapping, err = c.findRESTMapping(context.Background(), discoveryClient, gv, "Deployment")
+	if err != nil {
+		t.Fatalf("findRESTMapping (second): %v", err)
+	}
+	if mapping == nil || mapping.Resource.Resource != "deployments" {
+		t.Fatalf("unexpected mapping after 304: %+v", mapping)
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests total, got %d", requests)
+	}
+}
@@ -0,0 +1,72 @@
+package restmapper
+
+import (
+	"time"
+)
+
+// negativeCacheBaseTTL is how long a freshly-recorded "not found" answer is trusted
+// before it's reconfirmed with the server.
+const negativeCacheBaseTTL = 30 * time.Second
+
+// negativeCacheMaxTTL caps the exponential backoff applied to a group or GroupVersion
+// that keeps coming back not-found, so a long-dead lookup doesn't stop being reconfirmed
+// altogether.
+const negativeCacheMaxTTL = 10 * time.Minute
+
+// negativeEntry records that a group or GroupVersion was not found, and when it's next
+// worth asking the server again. misses doubles the backoff (capped at
+// negativeCacheMaxTTL) so a GVK that's persistently absent costs fewer and fewer
+// round-trips over time, while one that just flickered is reconfirmed quickly.
+type negativeEntry struct {
+	misses      int
+	nextAttempt time.Time
+}
+
+// recordMiss bumps the entry's miss count and schedules the next retry. It's also used
+// to create the first entry (on a zero-value *negativeEntry-to-be).
+func (e *negativeEntry) recordMiss() {
+	e.misses++
+	e.nextAttempt = time.Now().Add(negativeBackoff(e.misses))
+}
+
+// current reports whether the entry's backoff window hasn't elapsed yet, i.e. whether
+// callers can trust the cached "not found" answer without asking the server again.
+func (e *negativeEntry) current() bool {
+	return e != nil && time.Now().Before(e.nextAttempt)
+}
+
+// negativeBackoff returns the TTL before a negative entry should be reconfirmed,
+// doubling on each consecutive miss up to negativeCacheMaxTTL.
+func negativeBackoff(misses int) time.Duration {
+	ttl := negativeCacheBaseTTL
+	for i := 1; i < misses && ttl < negativeCacheMaxTTL; i++ {
+		ttl *= 2
+	}
+	if ttl > negativeCacheMaxTTL {
+		ttl = negativeCacheMaxTTL
+	}
+	return ttl
+}
+
+// recordNegativeGroupLocked records that groupName was not found in the last
+// ServerGroups() response. Callers must hold c.mutex.
+func (c *cache) recordNegativeGroupLocked(groupName string) {
+	if c.negativeGroups == nil {
+		c.negativeGroups = make(map[string]*negativeEntry)
+	}
+	entry := c.negativeGroups[groupName]
+	if entry == nil {
+		entry = &negativeEntry{}
+		c.negativeGroups[groupName] = entry
+	}
+	entry.recordMiss()
+}
+
+// recordNegative records that this GroupVersion was confirmed missing. Callers must
+// hold c.mutex (cachedGroupVersion's own mutex, not the parent cache's).
+func (c *cachedGroupVersion) recordNegative() {
+	if c.negative == nil {
+		c.negative = &negativeEntry{}
+	}
+	c.negative.recordMiss()
+}
@@ -0,0 +1,97 @@
+package restmapper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestFindRESTMapping_NegativeCacheShortCircuitsRepeatedMisses(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	discoveryClient := newFakeDiscoveryClient(t, server)
+	gv := schema.GroupVersion{Group: "widgets.example.com", Version: "v1"}
+
+	c := newCache()
+	for i := 0; i < 3; i++ {
+		mapping, err := c.findRESTMapping(context.Background(), discoveryClient, gv, "Widget")
+		if err != nil {
+			t.Fatalf("findRESTMapping: %v", err)
+		}
+		if mapping != nil {
+			t.Fatalf("expected no mapping for a nonexistent GV, got %+v", mapping)
+		}
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("expected exactly 1 request to the server (later calls should hit the negative cache), got %d", got)
+	}
+
+	cgv := c.groupVersions[gv]
+	if cgv == nil || cgv.negative == nil {
+		t.Fatalf("expected a negative entry to be recorded for %v", gv)
+	}
+	if cgv.negative.misses != 1 {
+		t.Fatalf("expected 1 miss recorded, got %d", cgv.negative.misses)
+	}
+}
+
+func TestNegativeBackoff_DoublesAndCaps(t *testing.T) {
+	cases := []struct {
+		misses   int
+		expected time.Duration
+	}{
+		{1, negativeCacheBaseTTL},
+		{2, 2 * negativeCacheBaseTTL},
+		{3, 4 * negativeCacheBaseTTL},
+	}
+	for _, tc := range cases {
+		if got := negativeBackoff(tc.misses); got != tc.expected {
+			t.Errorf("negativeBackoff(%d) = %v, want %v", tc.misses, got, tc.expected)
+		}
+	}
+
+	if got := negativeBackoff(20); got != negativeCacheMaxTTL {
+		t.Errorf("negativeBackoff(20) = %v, want cap %v", got, negativeCacheMaxTTL)
+	}
+}
+
+func TestFindGroupInfo_NegativeCache(t *testing.T) {
+	// discovery.DiscoveryClient.ServerGroups() issues two HTTP round trips under the hood
+	// (the legacy "/api" endpoint, then the grouped "/apis" endpoint), so one logical
+	// ServerGroups() call is two requests against this shared handler.
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"APIGroupList","apiVersion":"v1","groups":[]}`))
+	}))
+	defer server.Close()
+
+	discoveryClient := newFakeDiscoveryClient(t, server)
+	c := newCache()
+
+	for i := 0; i < 3; i++ {
+		_, found, err := c.findGroupInfo(context.Background(), discoveryClient, "missing.example.com")
+		if err != nil {
+			t.Fatalf("findGroupInfo: %v", err)
+		}
+		if found {
+			t.Fatalf("expected group to be absent")
+		}
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("expected exactly 1 logical ServerGroups call, i.e. 2 requests (later findGroupInfo calls should hit the negative cache), got %d", got)
+	}
+}
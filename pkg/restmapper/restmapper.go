@@ -0,0 +1,123 @@
+package restmapper
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+// RESTMapper is a discovery-backed, caching REST mapper for use by declarative-pattern
+// controllers and tooling. It wraps a cache of discovery information so that repeated
+// lookups for the same GroupVersionKind don't each pay a round-trip to the API server.
+type RESTMapper struct {
+	discovery discovery.DiscoveryInterface
+	cache     *cache
+}
+
+// Option configures a RESTMapper constructed with NewRESTMapper.
+type Option func(*cache)
+
+// WithTTL causes cached discovery information to be treated as stale, and re-fetched,
+// after the given duration. The default is no TTL: entries are kept until invalidated.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithAggregatedDiscovery enables populating the cache from a single Kubernetes 1.27+
+// aggregated discovery document (APIGroupDiscoveryList) instead of one discovery request
+// per group and per GroupVersion. If the API server doesn't support the aggregated
+// format, the cache transparently falls back to the legacy discovery calls.
+func WithAggregatedDiscovery(enabled bool) Option {
+	return func(c *cache) {
+		c.aggregatedDiscovery = enabled
+	}
+}
+
+// WithRESTClient supplies a raw rest.Interface to use for conditional (ETag-aware)
+// discovery requests when the discovery client passed to NewRESTMapper doesn't expose
+// one itself (its RESTClient() method returns nil). Without either, discovery refreshes
+// are always unconditional full re-downloads.
+func WithRESTClient(restClient rest.Interface) Option {
+	return func(c *cache) {
+		c.restClient = restClient
+	}
+}
+
+// WithWarmWorkers sets the maximum number of GroupVersions that Warm/WarmAll will fetch
+// concurrently. The default, used when n <= 0, is 8.
+func WithWarmWorkers(n int) Option {
+	return func(c *cache) {
+		c.warmWorkers = n
+	}
+}
+
+// WithDiskCache enables an on-disk discovery cache rooted at dir, in the same per-host
+// layout client-go's CachedDiscoveryClient uses (and therefore kubectl), so tools built on
+// this package can share cache state with kubectl and survive process restarts. maxAge
+// bounds how old an on-disk entry may be and still be trusted without reconfirming from
+// the server; a non-positive maxAge accepts entries of any age.
+//
+// dir == "" (the default) disables disk caching entirely, preserving the in-memory-only
+// behavior of a cache constructed without this option. Populating the disk cache relies on
+// being able to reach the discovery client's underlying host, so it requires either the
+// discovery client's own RESTClient() or one supplied via WithRESTClient.
+func WithDiskCache(dir string, maxAge time.Duration) Option {
+	return func(c *cache) {
+		c.diskCacheDir = dir
+		c.diskCacheMaxAge = maxAge
+	}
+}
+
+// NewRESTMapper constructs a RESTMapper backed by the given discovery client.
+func NewRESTMapper(discoveryClient discovery.DiscoveryInterface, opts ...Option) *RESTMapper {
+	c := newCache()
+	for _, opt := range opts {
+		opt(c)
+	}
+	return &RESTMapper{
+		discovery: discoveryClient,
+		cache:     c,
+	}
+}
+
+// RESTMapping returns the RESTMapping for the given GroupVersionKind, querying discovery
+// and populating the cache if this is the first time we've seen it.
+func (m *RESTMapper) RESTMapping(ctx context.Context, gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	return m.cache.findRESTMapping(ctx, m.discovery, gvk.GroupVersion(), gvk.Kind)
+}
+
+// Invalidate clears the entire cache, forcing the next lookup of any group or
+// GroupVersion to rediscover it from the server.
+func (m *RESTMapper) Invalidate() {
+	m.cache.Invalidate()
+}
+
+// InvalidateGroup clears the cached APIGroup entry for groupName, along with any cached
+// GroupVersions that belong to it.
+func (m *RESTMapper) InvalidateGroup(groupName string) {
+	m.cache.InvalidateGroup(groupName)
+}
+
+// InvalidateGroupVersion clears the cached resource information for gv, forcing the next
+// lookup for that GroupVersion to rediscover it from the server.
+func (m *RESTMapper) InvalidateGroupVersion(gv schema.GroupVersion) {
+	m.cache.InvalidateGroupVersion(gv)
+}
+
+// Warm concurrently populates the cache for each of the given GVKs, so that later
+// RESTMapping calls for them are served from cache instead of paying a synchronous
+// discovery round-trip.
+func (m *RESTMapper) Warm(ctx context.Context, gvks ...schema.GroupVersionKind) error {
+	return m.cache.Warm(ctx, m.discovery, gvks...)
+}
+
+// WarmAll concurrently populates the cache for every GroupVersion the server advertises.
+func (m *RESTMapper) WarmAll(ctx context.Context) error {
+	return m.cache.WarmAll(ctx, m.discovery)
+}
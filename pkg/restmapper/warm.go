@@ -0,0 +1,80 @@
+package restmapper
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/discovery"
+)
+
+// defaultWarmWorkers bounds the parallelism of Warm/WarmAll when WithWarmWorkers isn't used.
+const defaultWarmWorkers = 8
+
+// Warm concurrently populates the cache's GroupVersion entries for each of the given
+// GVKs, with bounded parallelism (see WithWarmWorkers). It's meant to be called once at
+// manager start-up with the GVKs a controller already knows it will reconcile (e.g. from
+// the declarative pattern's addon manifests), so the first real reconcile for each GVK
+// doesn't pay a synchronous discovery round-trip.
+func (c *cache) Warm(ctx context.Context, discoveryClient discovery.DiscoveryInterface, gvks ...schema.GroupVersionKind) error {
+	seen := make(map[schema.GroupVersion]bool, len(gvks))
+	gvs := make([]schema.GroupVersion, 0, len(gvks))
+	for _, gvk := range gvks {
+		gv := gvk.GroupVersion()
+		if !seen[gv] {
+			seen[gv] = true
+			gvs = append(gvs, gv)
+		}
+	}
+	return c.warmGroupVersions(ctx, discoveryClient, gvs)
+}
+
+// WarmAll warms every GroupVersion the server advertises, discovered via ServerGroups.
+func (c *cache) WarmAll(ctx context.Context, discoveryClient discovery.DiscoveryInterface) error {
+	serverGroups, err := discoveryClient.ServerGroups()
+	if err != nil {
+		return fmt.Errorf("error from ServerGroups: %w", err)
+	}
+
+	var gvs []schema.GroupVersion
+	for _, group := range serverGroups.Groups {
+		for _, v := range group.Versions {
+			gvs = append(gvs, schema.GroupVersion{Group: group.Name, Version: v.Version})
+		}
+	}
+	return c.warmGroupVersions(ctx, discoveryClient, gvs)
+}
+
+// warmGroupVersions fetches each of gvs concurrently, bounded by c.warmWorkers (default
+// defaultWarmWorkers) so that warming a large number of GroupVersions can't overwhelm
+// the API server with simultaneous requests.
+func (c *cache) warmGroupVersions(ctx context.Context, discoveryClient discovery.DiscoveryInterface, gvs []schema.GroupVersion) error {
+	workers := c.warmWorkers
+	if workers <= 0 {
+		workers = defaultWarmWorkers
+	}
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	errs := make([]error, len(gvs))
+
+	for i, gv := range gvs {
+		i, gv := i, gv
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			cached := c.getOrCreateGroupVersion(gv)
+			_, err := cached.fetch(ctx, discoveryClient)
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	return utilerrors.NewAggregate(errs)
+}
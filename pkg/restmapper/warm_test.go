@@ -0,0 +1,117 @@
+package restmapper
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// concurrentGVServer serves APIResourceList documents for /apis/<group>/v1, sleeping
+// delay before answering each request and tracking the peak number of requests that were
+// in flight at once, so tests can assert that fetching different GVs doesn't serialize.
+type concurrentGVServer struct {
+	*httptest.Server
+	inFlight int32
+	peak     int32
+}
+
+func newConcurrentGVServer(t *testing.T, groups []string, delay time.Duration) *concurrentGVServer {
+	t.Helper()
+	s := &concurrentGVServer{}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/apis":
+			apiGroupList := metav1.APIGroupList{}
+			for _, g := range groups {
+				apiGroupList.Groups = append(apiGroupList.Groups, metav1.APIGroup{
+					Name:             g,
+					Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: g + "/v1", Version: "v1"}},
+					PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: g + "/v1", Version: "v1"},
+				})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(apiGroupList)
+			return
+		}
+
+		inFlight := atomic.AddInt32(&s.inFlight, 1)
+		for {
+			peak := atomic.LoadInt32(&s.peak)
+			if inFlight <= peak || atomic.CompareAndSwapInt32(&s.peak, peak, inFlight) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		atomic.AddInt32(&s.inFlight, -1)
+
+		resourceList := metav1.APIResourceList{
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Kind: "Widget", Namespaced: true},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resourceList)
+	}))
+	return s
+}
+
+func TestWarm_FetchesGroupVersionsConcurrently(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	server := newConcurrentGVServer(t, []string{"g1", "g2"}, delay)
+	defer server.Close()
+
+	discoveryClient := newFakeDiscoveryClient(t, server.Server)
+	c := newCache()
+
+	start := time.Now()
+	err := c.Warm(context.Background(), discoveryClient,
+		schema.GroupVersionKind{Group: "g1", Version: "v1", Kind: "Widget"},
+		schema.GroupVersionKind{Group: "g2", Version: "v1", Kind: "Widget"},
+	)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("Warm: %v", err)
+	}
+	if elapsed >= 2*delay {
+		t.Fatalf("Warm took %v, expected the two GVs to be fetched concurrently (< %v)", elapsed, 2*delay)
+	}
+	if peak := atomic.LoadInt32(&server.peak); peak < 2 {
+		t.Fatalf("expected at least 2 concurrent in-flight requests, got peak=%d", peak)
+	}
+
+	for _, gv := range []schema.GroupVersion{{Group: "g1", Version: "v1"}, {Group: "g2", Version: "v1"}} {
+		cgv, found := c.groupVersions[gv]
+		if !found {
+			t.Fatalf("expected %v to be warmed", gv)
+		}
+		if cgv.kinds["Widget"].Resource != "widgets" {
+			t.Fatalf("expected Widget -> widgets mapping for %v, got %+v", gv, cgv.kinds)
+		}
+	}
+}
+
+func TestWarmAll_DiscoversGroupVersionsFromServerGroups(t *testing.T) {
+	server := newConcurrentGVServer(t, []string{"g1", "g2"}, 0)
+	defer server.Close()
+
+	discoveryClient := newFakeDiscoveryClient(t, server.Server)
+	c := newCache()
+
+	if err := c.WarmAll(context.Background(), discoveryClient); err != nil {
+		t.Fatalf("WarmAll: %v", err)
+	}
+
+	for _, gv := range []schema.GroupVersion{{Group: "g1", Version: "v1"}, {Group: "g2", Version: "v1"}} {
+		if _, found := c.groupVersions[gv]; !found {
+			t.Fatalf("expected %v to be warmed by WarmAll", gv)
+		}
+	}
+}